@@ -0,0 +1,72 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// ActiveDirectoryProvider implements DirectoryProvider for Microsoft Active
+// Directory: UserAccountControl bit manipulation for enable/disable, and
+// pwdLastSet ticks for password age.
+type ActiveDirectoryProvider struct{}
+
+func (p *ActiveDirectoryProvider) PasswordLastSet(entry *Entry) (time.Time, error) {
+	values, found := entry.Get(FieldRegistry.PasswordLastSet)
+	if !found {
+		return time.Time{}, fmt.Errorf("%+v lacks a PasswordLastSet field", entry)
+	}
+	if len(values) != 1 {
+		return time.Time{}, fmt.Errorf("expected only one value for PasswordLastSet, but received %s", values)
+	}
+
+	ticks := values[0]
+	if ticks == "0" {
+		// password has never been rolled in Active Directory, only created
+		return time.Time{}, nil
+	}
+	return ParseTicks(ticks)
+}
+
+func (p *ActiveDirectoryProvider) EncodePassword(newPassword string) (*Field, string, error) {
+	encoded, err := formatUnicodePwd(newPassword)
+	if err != nil {
+		return nil, "", err
+	}
+	return FieldRegistry.UnicodePwd, encoded, nil
+}
+
+func (p *ActiveDirectoryProvider) IsDisabled(entry *Entry) (bool, error) {
+	uac, err := p.userAccountControl(entry)
+	if err != nil {
+		return false, err
+	}
+	return uac.Has(ACCOUNTDISABLE), nil
+}
+
+func (p *ActiveDirectoryProvider) EncodeDisabled(entry *Entry, disabled bool) (*Field, string, error) {
+	uac, err := p.userAccountControl(entry)
+	if err != nil {
+		return nil, "", err
+	}
+	if disabled {
+		uac.Add(ACCOUNTDISABLE)
+	} else {
+		uac.Clear(ACCOUNTDISABLE)
+	}
+	return FieldRegistry.UserAccountControl, fmt.Sprintf("%d", uint32(uac)), nil
+}
+
+func (p *ActiveDirectoryProvider) userAccountControl(entry *Entry) (Bits, error) {
+	values, found := entry.Get(FieldRegistry.UserAccountControl)
+	if !found {
+		return Bits(0), fmt.Errorf("%+v lacks a UserAccountControl field", entry)
+	}
+	if len(values) != 1 {
+		return Bits(0), fmt.Errorf("expected only one value for UserAccountControl, but received %s", values)
+	}
+	var uac uint32
+	if _, err := fmt.Sscanf(values[0], "%d", &uac); err != nil {
+		return Bits(0), fmt.Errorf("unable to parse UserAccountControl value %q: %w", values[0], err)
+	}
+	return Bits(uac), nil
+}
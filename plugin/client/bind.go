@@ -0,0 +1,32 @@
+package client
+
+// BindSASLMechanism selects how SecretsClient authenticates its root bind,
+// and correspondingly what UpdateRootCredential rotates. The zero value,
+// SimpleBind, preserves the pre-existing behavior for configs written before
+// this setting existed.
+type BindSASLMechanism string
+
+const (
+	// SimpleBind authenticates with bind_dn and bind_password, as this
+	// package has always done. UpdateRootCredential rotates the password.
+	SimpleBind BindSASLMechanism = ""
+
+	// ExternalBind authenticates over LDAPS by presenting a client
+	// certificate mapped to the bind DN, in place of a long-lived
+	// bind_password. UpdateRootCredential rotates the certificate instead.
+	// Actually presenting the certificate during the LDAP bind is
+	// connection-establishment logic that lives in Client's dialing code,
+	// which isn't part of this chunk of the repo.
+	ExternalBind BindSASLMechanism = "EXTERNAL"
+)
+
+// CertIssuer re-issues the client certificate/key pair for an ExternalBind
+// rotation, e.g. backed by a Vault PKI secrets engine mount.
+// UpdateRootCredential writes only the certificate to the bind DN. This package doesn't implement one, since doing
+// so means calling back into Vault's own PKI backend rather than an LDAP
+// operation.
+type CertIssuer interface {
+	// IssueCertificate returns a freshly issued DER-encoded certificate and
+	// its PEM-encoded private key.
+	IssueCertificate() (derCert []byte, keyPEM []byte, err error)
+}
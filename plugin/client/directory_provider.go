@@ -0,0 +1,53 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// DirectoryProvider abstracts the directory-specific mechanics of password
+// rotation and account lifecycle management, so SecretsClient's higher-level
+// flows (rotate, disable, enable) don't need to branch on vendor. Active
+// Directory's UAC bit manipulation, unicodePwd UTF-16LE encoding, and
+// pwdLastSet tick parsing live behind ActiveDirectoryProvider; OpenLDAP and
+// FreeIPA get their own implementations of the same seams.
+type DirectoryProvider interface {
+	// PasswordLastSet returns when entry's password was last rotated.
+	PasswordLastSet(entry *Entry) (time.Time, error)
+
+	// EncodePassword returns the attribute and value to write to entry in
+	// order to rotate its password to newPassword.
+	EncodePassword(newPassword string) (*Field, string, error)
+
+	// IsDisabled reports whether entry's account is currently disabled.
+	IsDisabled(entry *Entry) (bool, error)
+
+	// EncodeDisabled returns the attribute and value to write to entry in
+	// order to set its disabled state.
+	EncodeDisabled(entry *Entry, disabled bool) (*Field, string, error)
+}
+
+// DirectoryType selects which DirectoryProvider a config should use. The
+// zero value, ActiveDirectory, preserves the pre-existing behavior for
+// configs written before this setting existed.
+type DirectoryType string
+
+const (
+	ActiveDirectory DirectoryType = ""
+	OpenLDAP        DirectoryType = "openldap"
+	FreeIPA         DirectoryType = "freeipa"
+)
+
+// ProviderFor returns the DirectoryProvider that directoryType selects.
+func ProviderFor(directoryType DirectoryType) (DirectoryProvider, error) {
+	switch directoryType {
+	case ActiveDirectory:
+		return &ActiveDirectoryProvider{}, nil
+	case OpenLDAP:
+		return &OpenLDAPProvider{}, nil
+	case FreeIPA:
+		return &FreeIPAProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported directory_type %q", directoryType)
+	}
+}
@@ -0,0 +1,22 @@
+package client
+
+// Field identifies an LDAP attribute, used both as a search filter key and
+// an Entry value key.
+type Field struct {
+	str string
+}
+
+// NewField returns a Field for attrName. It's for referencing attributes
+// that aren't part of the predefined FieldRegistry, such as an
+// operator-configured custom schema attribute.
+func NewField(attrName string) *Field {
+	return &Field{str: attrName}
+}
+
+func (f *Field) String() string {
+	return f.str
+}
+
+// userPasswordField is the standard LDAP userPassword attribute, shared by
+// the OpenLDAP and FreeIPA DirectoryProviders.
+var userPasswordField = NewField("userPassword")
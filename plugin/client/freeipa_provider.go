@@ -0,0 +1,44 @@
+package client
+
+import (
+	"time"
+)
+
+// FreeIPAProvider implements DirectoryProvider for FreeIPA: its Kerberos
+// schema's krbPasswordExpiration for password age, and nsAccountLock for
+// account disablement. Neither is part of FieldRegistry, so they're built
+// with NewField.
+var (
+	freeIPAKrbPasswordExpirationField = NewField("krbPasswordExpiration")
+	freeIPANsAccountLockField         = NewField("nsAccountLock")
+)
+
+type FreeIPAProvider struct{}
+
+func (p *FreeIPAProvider) PasswordLastSet(entry *Entry) (time.Time, error) {
+	values, found := entry.Get(freeIPAKrbPasswordExpirationField)
+	if !found || len(values) != 1 {
+		return time.Time{}, nil
+	}
+	return time.Parse("20060102150405Z", values[0])
+}
+
+func (p *FreeIPAProvider) EncodePassword(newPassword string) (*Field, string, error) {
+	return userPasswordField, newPassword, nil
+}
+
+func (p *FreeIPAProvider) IsDisabled(entry *Entry) (bool, error) {
+	values, found := entry.Get(freeIPANsAccountLockField)
+	if !found || len(values) != 1 {
+		return false, nil
+	}
+	return values[0] == "TRUE", nil
+}
+
+func (p *FreeIPAProvider) EncodeDisabled(entry *Entry, disabled bool) (*Field, string, error) {
+	value := "FALSE"
+	if disabled {
+		value = "TRUE"
+	}
+	return freeIPANsAccountLockField, value, nil
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// OpenLDAPProvider implements DirectoryProvider for OpenLDAP: userPassword
+// for credentials, pwdChangedTime for password age, and the ppolicy
+// overlay's pwdAccountLockedTime for lockout. None of these are part of
+// FieldRegistry, so they're built with NewField.
+var (
+	openLDAPPwdChangedTimeField       = NewField("pwdChangedTime")
+	openLDAPPwdAccountLockedTimeField = NewField("pwdAccountLockedTime")
+)
+
+type OpenLDAPProvider struct{}
+
+func (p *OpenLDAPProvider) PasswordLastSet(entry *Entry) (time.Time, error) {
+	values, found := entry.Get(openLDAPPwdChangedTimeField)
+	if !found {
+		// ppolicy only starts tracking pwdChangedTime after the first rotation.
+		return time.Time{}, nil
+	}
+	if len(values) != 1 {
+		return time.Time{}, fmt.Errorf("expected only one value for pwdChangedTime, but received %s", values)
+	}
+	return time.Parse("20060102150405Z", values[0])
+}
+
+func (p *OpenLDAPProvider) EncodePassword(newPassword string) (*Field, string, error) {
+	return userPasswordField, newPassword, nil
+}
+
+func (p *OpenLDAPProvider) IsDisabled(entry *Entry) (bool, error) {
+	values, found := entry.Get(openLDAPPwdAccountLockedTimeField)
+	return found && len(values) > 0 && values[0] != "", nil
+}
+
+func (p *OpenLDAPProvider) EncodeDisabled(entry *Entry, disabled bool) (*Field, string, error) {
+	if !disabled {
+		return openLDAPPwdAccountLockedTimeField, "", nil
+	}
+	return openLDAPPwdAccountLockedTimeField, "000001010000Z", nil
+}
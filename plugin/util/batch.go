@@ -0,0 +1,229 @@
+package util
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/vault-plugin-secrets-ad/plugin/client"
+)
+
+// DefaultBatchPageSize is the maximum number of account names the Many
+// operations put into a single search filter when the caller doesn't
+// specify one.
+const DefaultBatchPageSize = 100
+
+// DefaultBatchConcurrency caps how many modifies the Many operations have
+// in flight at once.
+const DefaultBatchConcurrency = 10
+
+// BatchOpts controls paging and concurrency for the Many operations.
+type BatchOpts struct {
+	// PageSize is the maximum number of account names searched for with a
+	// single filter; longer lists are split into several searches. It is not
+	// sent as an LDAP paged results control (RFC 2696). Defaults to
+	// DefaultBatchPageSize when zero.
+	PageSize int
+
+	// Concurrency caps how many entries are modified at once. Defaults to
+	// DefaultBatchConcurrency when zero.
+	Concurrency int
+}
+
+func (o BatchOpts) withDefaults() BatchOpts {
+	if o.PageSize == 0 {
+		o.PageSize = DefaultBatchPageSize
+	}
+	if o.Concurrency == 0 {
+		o.Concurrency = DefaultBatchConcurrency
+	}
+	return o
+}
+
+// validate rejects nonsensical settings, such as a negative Concurrency that
+// would otherwise reach make(chan struct{}, n) and panic.
+func (o BatchOpts) validate() error {
+	if o.PageSize <= 0 {
+		return fmt.Errorf("page size must be greater than zero, but was %d", o.PageSize)
+	}
+	if o.Concurrency <= 0 {
+		return fmt.Errorf("concurrency must be greater than zero, but was %d", o.Concurrency)
+	}
+	return nil
+}
+
+// BatchError collects the per-entry failures from a Many operation. A
+// partial failure doesn't abort the rest of the batch.
+type BatchError struct {
+	Failures map[string]error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of the batch's entries failed", len(e.Failures))
+}
+
+func newBatchError() *BatchError {
+	return &BatchError{Failures: map[string]error{}}
+}
+
+func (e *BatchError) add(serviceAccountName string, err error) {
+	e.Failures[serviceAccountName] = err
+}
+
+func (e *BatchError) orNil() error {
+	if len(e.Failures) == 0 {
+		return nil
+	}
+	return e
+}
+
+// GetMany looks up serviceAccountNames, grouped by the (possibly
+// hierarchical, see splitHierarchicalName) search base their names resolve
+// to, and paged in batches of opts.PageSize, each searched with a single OR
+// filter over UserPrincipalName rather than one bind+search per account. The
+// result is keyed by the original serviceAccountNames.
+func (c *SecretsClient) GetMany(conf *client.ADConf, serviceAccountNames []string, opts BatchOpts) (map[string]*client.Entry, error) {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	// groupsByBase groups the leaf account names to search for by the
+	// base their hierarchical name resolves to, along with a reverse lookup
+	// back to the original, full serviceAccountName.
+	type group struct {
+		accountNames []string
+		fullNames    map[string]string
+	}
+	groupsByBase := map[string]*group{}
+	for _, serviceAccountName := range serviceAccountNames {
+		ouPath, accountName, err := splitHierarchicalName(serviceAccountName)
+		if err != nil {
+			return nil, err
+		}
+		base := searchBase(conf.UserDN, ouPath)
+
+		g, ok := groupsByBase[base]
+		if !ok {
+			g = &group{fullNames: map[string]string{}}
+			groupsByBase[base] = g
+		}
+		g.accountNames = append(g.accountNames, accountName)
+		g.fullNames[accountName] = serviceAccountName
+	}
+
+	results := make(map[string]*client.Entry, len(serviceAccountNames))
+	for base, g := range groupsByBase {
+		for start := 0; start < len(g.accountNames); start += opts.PageSize {
+			end := start + opts.PageSize
+			if end > len(g.accountNames) {
+				end = len(g.accountNames)
+			}
+			page := g.accountNames[start:end]
+
+			filters := map[*client.Field][]string{
+				client.FieldRegistry.UserPrincipalName: page,
+			}
+			entries, err := c.adClient.Search(conf, base, filters)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				values, found := entry.Get(client.FieldRegistry.UserPrincipalName)
+				if !found || len(values) != 1 {
+					continue
+				}
+				fullName, ok := g.fullNames[values[0]]
+				if !ok {
+					continue
+				}
+				results[fullName] = entry
+			}
+		}
+	}
+	return results, nil
+}
+
+// UpdatePasswordMany rotates newPasswords for each of its service accounts.
+// It resolves every account in one pass via GetMany's grouped/paged search,
+// rather than repeating a search per account, then issues the modifies with
+// up to opts.Concurrency in flight at once. Each modify is its own
+// client.Client UpdateEntry call; they don't share a connection opened here.
+// A single account's failure doesn't abort the rest of the batch; failures
+// are returned together as a *BatchError.
+func (c *SecretsClient) UpdatePasswordMany(conf *client.ADConf, newPasswords map[string]string, opts BatchOpts) error {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	serviceAccountNames := make([]string, 0, len(newPasswords))
+	for serviceAccountName := range newPasswords {
+		serviceAccountNames = append(serviceAccountNames, serviceAccountName)
+	}
+
+	entries, err := c.GetMany(conf, serviceAccountNames, opts)
+	if err != nil {
+		return err
+	}
+
+	return runBatch(opts, serviceAccountNames, func(serviceAccountName string) error {
+		entry, found := entries[serviceAccountName]
+		if !found {
+			return fmt.Errorf("unable to find service account named %s in active directory, searches are case sensitive", serviceAccountName)
+		}
+		return c.updateEntryPassword(conf, entry, newPasswords[serviceAccountName])
+	})
+}
+
+// DisableAccountMany disables each of serviceAccountNames. It resolves every
+// account in one pass via GetMany's grouped/paged search, rather than
+// repeating a search per account, then issues the modifies with up to
+// opts.Concurrency in flight at once. Each modify is its own client.Client
+// UpdateEntry call; they don't share a connection opened here. A single
+// account's failure doesn't abort the rest of the batch; failures are
+// returned together as a *BatchError.
+func (c *SecretsClient) DisableAccountMany(conf *client.ADConf, serviceAccountNames []string, opts BatchOpts) error {
+	opts = opts.withDefaults()
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	entries, err := c.GetMany(conf, serviceAccountNames, opts)
+	if err != nil {
+		return err
+	}
+
+	return runBatch(opts, serviceAccountNames, func(serviceAccountName string) error {
+		entry, found := entries[serviceAccountName]
+		if !found {
+			return fmt.Errorf("unable to find service account named %s in active directory, searches are case sensitive", serviceAccountName)
+		}
+		return c.setEntryDisabled(conf, serviceAccountName, entry, true)
+	})
+}
+
+// runBatch fans workFn out over serviceAccountNames with up to
+// opts.Concurrency goroutines in flight, aggregating any errors into a
+// *BatchError rather than aborting on the first one.
+func runBatch(opts BatchOpts, serviceAccountNames []string, workFn func(serviceAccountName string) error) error {
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	batchErr := newBatchError()
+
+	for _, serviceAccountName := range serviceAccountNames {
+		serviceAccountName := serviceAccountName
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := workFn(serviceAccountName); err != nil {
+				mu.Lock()
+				batchErr.add(serviceAccountName, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return batchErr.orNil()
+}
@@ -0,0 +1,55 @@
+package util
+
+import "testing"
+
+func TestBatchOptsValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		opts    BatchOpts
+		wantErr bool
+	}{
+		{"defaults", BatchOpts{}.withDefaults(), false},
+		{"negative concurrency", BatchOpts{PageSize: 10, Concurrency: -1}, true},
+		{"negative page size", BatchOpts{PageSize: -1, Concurrency: 10}, true},
+		{"zero concurrency not defaulted", BatchOpts{PageSize: 10, Concurrency: 0}, true},
+	}
+
+	for _, tc := range cases {
+		err := tc.opts.validate()
+		if tc.wantErr && err == nil {
+			t.Errorf("%s: expected an error, got none", tc.name)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("%s: expected no error, got %s", tc.name, err)
+		}
+	}
+}
+
+func TestRunBatchAggregatesFailuresWithoutAborting(t *testing.T) {
+	opts := BatchOpts{PageSize: 10, Concurrency: 2}
+	names := []string{"a", "b", "c"}
+
+	err := runBatch(opts, names, func(serviceAccountName string) error {
+		if serviceAccountName == "b" {
+			return errTestBatch
+		}
+		return nil
+	})
+
+	batchErr, ok := err.(*BatchError)
+	if !ok {
+		t.Fatalf("expected a *BatchError, got %T: %v", err, err)
+	}
+	if len(batchErr.Failures) != 1 {
+		t.Fatalf("expected exactly one failure, got %+v", batchErr.Failures)
+	}
+	if _, found := batchErr.Failures["b"]; !found {
+		t.Fatalf("expected \"b\" to have failed, got %+v", batchErr.Failures)
+	}
+}
+
+var errTestBatch = &testBatchError{}
+
+type testBatchError struct{}
+
+func (e *testBatchError) Error() string { return "intentional test failure" }
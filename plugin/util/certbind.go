@@ -0,0 +1,32 @@
+package util
+
+import (
+	"github.com/hashicorp/vault-plugin-secrets-ad/plugin/client"
+)
+
+// userCertificateField is the directory attribute a bind DN's SASL EXTERNAL
+// client certificate is written to. It's not part of FieldRegistry since
+// it's only ever used for this one, root-rotation-only operation.
+var userCertificateField = client.NewField("userCertificate")
+
+// UpdateRootCertificate rotates the bind DN's client certificate used for
+// SASL EXTERNAL binds over LDAPS, writing the new DER-encoded certificate to
+// the userCertificate attribute. It's the certificate-bind counterpart to
+// UpdateRootPassword: when SecretsClient.BindSASLMechanism is
+// client.ExternalBind, UpdateRootCredential calls this instead, after
+// re-issuing the certificate through SecretsClient.CertIssuer. Actually
+// presenting the certificate as TLS client auth during the bind itself is
+// connection-establishment logic that belongs in client.Client's dialing
+// code, which isn't part of this chunk of the repo.
+func (c *SecretsClient) UpdateRootCertificate(conf *client.ADConf, bindDN string, derCert []byte) error {
+	filters := map[*client.Field][]string{
+		client.FieldRegistry.DistinguishedName: {bindDN},
+	}
+	newValues := map[*client.Field][]string{
+		userCertificateField: {string(derCert)},
+	}
+	// As with UpdateRootPassword, search under the bind DN rather than the
+	// managed users' UserDN, since the bind account may live in a separate
+	// part of the tree.
+	return c.adClient.UpdateEntry(conf, conf.BindDN, filters, newValues)
+}
@@ -0,0 +1,9 @@
+package util
+
+import "testing"
+
+func TestUserCertificateFieldIsNamedUserCertificate(t *testing.T) {
+	if got := userCertificateField.String(); got != "userCertificate" {
+		t.Fatalf("expected attribute \"userCertificate\", got %q", got)
+	}
+}
@@ -0,0 +1,159 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault-plugin-secrets-ad/plugin/client"
+)
+
+// memberOfField and memberField aren't part of FieldRegistry, so they're
+// built with client.NewField like the SCRAM and userCertificate attributes.
+var (
+	memberOfField = client.NewField("memberOf")
+	memberField   = client.NewField("member")
+)
+
+// ListGroups returns the distinguished names a service account is currently
+// a member of, via its memberOf attribute.
+func (c *SecretsClient) ListGroups(conf *client.ADConf, serviceAccountName string) ([]string, error) {
+	entry, err := c.Get(conf, serviceAccountName)
+	if err != nil {
+		return nil, err
+	}
+	groups, _ := entry.Get(memberOfField)
+	return groups, nil
+}
+
+// AddToGroup adds a service account to groupDN's member attribute, leaving
+// any of the group's other members in place. It reports whether the account
+// was actually added; adding an existing member is a no-op.
+func (c *SecretsClient) AddToGroup(conf *client.ADConf, serviceAccountName string, groupDN string) (bool, error) {
+	entry, err := c.Get(conf, serviceAccountName)
+	if err != nil {
+		return false, err
+	}
+	group, err := c.getGroup(conf, groupDN)
+	if err != nil {
+		return false, err
+	}
+
+	members, _ := group.Get(memberField)
+	for _, member := range members {
+		if member == entry.DN {
+			return false, nil
+		}
+	}
+	if err := c.setGroupMembers(conf, groupDN, append(members, entry.DN)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveFromGroup removes a service account from groupDN's member
+// attribute, leaving any of the group's other members in place. It reports
+// whether the account was actually removed; removing an absent member is a
+// no-op.
+func (c *SecretsClient) RemoveFromGroup(conf *client.ADConf, serviceAccountName string, groupDN string) (bool, error) {
+	entry, err := c.Get(conf, serviceAccountName)
+	if err != nil {
+		return false, err
+	}
+	group, err := c.getGroup(conf, groupDN)
+	if err != nil {
+		return false, err
+	}
+
+	members, _ := group.Get(memberField)
+	remaining := make([]string, 0, len(members))
+	found := false
+	for _, member := range members {
+		if member == entry.DN {
+			found = true
+			continue
+		}
+		remaining = append(remaining, member)
+	}
+	if !found {
+		return false, nil
+	}
+	if err := c.setGroupMembers(conf, groupDN, remaining); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *SecretsClient) getGroup(conf *client.ADConf, groupDN string) (*client.Entry, error) {
+	filters := map[*client.Field][]string{
+		client.FieldRegistry.DistinguishedName: {groupDN},
+	}
+	entries, err := c.adClient.Search(conf, groupDN, filters)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 {
+		return nil, fmt.Errorf("expected one group entry for %s, but received %+v", groupDN, entries)
+	}
+	return entries[0], nil
+}
+
+func (c *SecretsClient) setGroupMembers(conf *client.ADConf, groupDN string, members []string) error {
+	filters := map[*client.Field][]string{
+		client.FieldRegistry.DistinguishedName: {groupDN},
+	}
+	newValues := map[*client.Field][]string{
+		memberField: members,
+	}
+	return c.adClient.UpdateEntry(conf, groupDN, filters, newValues)
+}
+
+// UpdatePasswordAndGroups rotates a service account's password and then adds
+// it to addGroups and removes it from removeGroups, as used for library
+// check-out and check-in. If any group change fails, every group change this
+// call actually applied - both additions and removals - is reverted so the
+// account isn't left in a partially-granted state. A group the account was
+// already a (non-)member of before this call is left untouched, since this
+// call didn't change it and rolling it back would corrupt membership held
+// independently of this checkout. The password itself is left rotated,
+// since it isn't a privilege escalation to undo.
+func (c *SecretsClient) UpdatePasswordAndGroups(conf *client.ADConf, serviceAccountName string, newPassword string, addGroups []string, removeGroups []string) error {
+	if err := c.UpdatePassword(conf, serviceAccountName, newPassword); err != nil {
+		return err
+	}
+
+	var added, removed []string
+	rollBack := func() {
+		for _, groupDN := range added {
+			if _, err := c.RemoveFromGroup(conf, serviceAccountName, groupDN); err != nil {
+				c.Logger.Warn(fmt.Sprintf("unable to roll back adding %s to group %s: %s", serviceAccountName, groupDN, err))
+			}
+		}
+		for _, groupDN := range removed {
+			if _, err := c.AddToGroup(conf, serviceAccountName, groupDN); err != nil {
+				c.Logger.Warn(fmt.Sprintf("unable to roll back removing %s from group %s: %s", serviceAccountName, groupDN, err))
+			}
+		}
+	}
+
+	for _, groupDN := range addGroups {
+		changed, err := c.AddToGroup(conf, serviceAccountName, groupDN)
+		if err != nil {
+			rollBack()
+			return fmt.Errorf("failed to add %s to group %s, rolled back group changes: %w", serviceAccountName, groupDN, err)
+		}
+		if changed {
+			added = append(added, groupDN)
+		}
+	}
+
+	for _, groupDN := range removeGroups {
+		changed, err := c.RemoveFromGroup(conf, serviceAccountName, groupDN)
+		if err != nil {
+			rollBack()
+			return fmt.Errorf("failed to remove %s from group %s, rolled back group changes: %w", serviceAccountName, groupDN, err)
+		}
+		if changed {
+			removed = append(removed, groupDN)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitHierarchicalName(t *testing.T) {
+	cases := []struct {
+		name            string
+		serviceAccount  string
+		wantOUPath      []string
+		wantAccountName string
+		wantErr         bool
+	}{
+		{"flat name", "db1", nil, "db1", false},
+		{"hierarchical name", "svc/prod/db1", []string{"svc", "prod"}, "db1", false},
+		{"empty name", "", nil, "", true},
+		{"double slash", "svc//db1", nil, "", true},
+		{"leading slash", "/svc/db1", nil, "", true},
+		{"trailing slash", "svc/db1/", nil, "", true},
+	}
+
+	for _, tc := range cases {
+		ouPath, accountName, err := splitHierarchicalName(tc.serviceAccount)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, got none", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(ouPath, tc.wantOUPath) {
+			t.Errorf("%s: expected OU path %v, got %v", tc.name, tc.wantOUPath, ouPath)
+		}
+		if accountName != tc.wantAccountName {
+			t.Errorf("%s: expected account name %q, got %q", tc.name, tc.wantAccountName, accountName)
+		}
+	}
+}
+
+func TestSearchBase(t *testing.T) {
+	defaultBase := "DC=example,DC=com"
+
+	if got := searchBase(defaultBase, nil); got != defaultBase {
+		t.Errorf("expected %q, got %q", defaultBase, got)
+	}
+
+	want := "OU=prod,OU=svc,DC=example,DC=com"
+	if got := searchBase(defaultBase, []string{"svc", "prod"}); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
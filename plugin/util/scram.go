@@ -0,0 +1,133 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/vault-plugin-secrets-ad/plugin/client"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// DefaultSCRAMIterations is used when a role doesn't specify its own
+// iteration count for PBKDF2-HMAC-SHA256 salted password derivation.
+const DefaultSCRAMIterations = 4096
+
+// scramSaltBytes is the size of the random salt generated for each
+// credential, per RFC 5802's recommendation of at least 128 bits.
+const scramSaltBytes = 16
+
+// SCRAMCredential holds the server-side SCRAM-SHA-256 verifier: the salt,
+// iteration count, StoredKey and ServerKey a server needs to check a
+// client's proof. It doesn't hold the ClientKey or SaltedPassword a client
+// needs to compute that proof. It's the JSON-encoded value written to the
+// directory's SCRAM attribute.
+type SCRAMCredential struct {
+	Salt       string `json:"salt"`
+	Iterations int    `json:"iterations"`
+	StoredKey  string `json:"stored_key"`
+	ServerKey  string `json:"server_key"`
+}
+
+// UpdateSCRAMPasswordOpts controls how UpdateSCRAMPassword writes the derived
+// credential alongside (or instead of) the account's legacy password
+// attribute.
+type UpdateSCRAMPasswordOpts struct {
+	// Attribute is the directory attribute the JSON-encoded SCRAMCredential
+	// is written to, e.g. a custom schema attribute or userParameters.
+	Attribute string
+
+	// Iterations is the PBKDF2 iteration count. Defaults to
+	// DefaultSCRAMIterations when zero.
+	Iterations int
+
+	// SetUnicodePwd also rotates the account's password through
+	// UpdatePassword, which writes the configured DirectoryProvider's
+	// password attribute (unicodePwd on Active Directory), for compatibility
+	// with callers that aren't SASL/SCRAM aware yet.
+	SetUnicodePwd bool
+}
+
+// UpdateSCRAMPassword rotates newPassword into a SCRAM-SHA-256 credential and
+// writes it to the configured attribute, optionally also rotating the
+// account's password through UpdatePassword for backwards compatibility.
+func (c *SecretsClient) UpdateSCRAMPassword(conf *client.ADConf, serviceAccountName string, newPassword string, opts UpdateSCRAMPasswordOpts) (*SCRAMCredential, error) {
+	if opts.Attribute == "" {
+		return nil, fmt.Errorf("a SCRAM attribute must be configured to store the derived credential")
+	}
+	iterations := opts.Iterations
+	if iterations == 0 {
+		iterations = DefaultSCRAMIterations
+	}
+
+	cred, err := newSCRAMCredential(newPassword, iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(cred)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode SCRAM credential: %w", err)
+	}
+
+	ouPath, accountName, err := splitHierarchicalName(serviceAccountName)
+	if err != nil {
+		return nil, err
+	}
+	filters := map[*client.Field][]string{
+		client.FieldRegistry.UserPrincipalName: {accountName},
+	}
+	newValues := map[*client.Field][]string{
+		client.NewField(opts.Attribute): {string(encoded)},
+	}
+	if err := c.adClient.UpdateEntry(conf, searchBase(conf.UserDN, ouPath), filters, newValues); err != nil {
+		return nil, err
+	}
+
+	if opts.SetUnicodePwd {
+		if err := c.UpdatePassword(conf, serviceAccountName, newPassword); err != nil {
+			return nil, err
+		}
+	}
+	return cred, nil
+}
+
+// newSCRAMCredential derives a SCRAM-SHA-256 credential from password,
+// following RFC 5802: a random salt is used to PBKDF2-derive a
+// SaltedPassword, from which ClientKey=HMAC(SaltedPassword,"Client Key") and
+// ServerKey=HMAC(SaltedPassword,"Server Key") are computed, and
+// StoredKey=SHA256(ClientKey) is kept so the raw ClientKey is never
+// persisted.
+func newSCRAMCredential(password string, iterations int) (*SCRAMCredential, error) {
+	salt := make([]byte, scramSaltBytes)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("unable to generate SCRAM salt: %w", err)
+	}
+	return deriveSCRAMCredential(password, salt, iterations), nil
+}
+
+// deriveSCRAMCredential does the actual RFC 5802 derivation for a given
+// salt, split out from newSCRAMCredential so it can be exercised
+// deterministically in tests.
+func deriveSCRAMCredential(password string, salt []byte, iterations int) *SCRAMCredential {
+	saltedPassword := pbkdf2.Key([]byte(password), salt, iterations, sha256.Size, sha256.New)
+	clientKey := hmacSHA256(saltedPassword, []byte("Client Key"))
+	storedKey := sha256.Sum256(clientKey)
+	serverKey := hmacSHA256(saltedPassword, []byte("Server Key"))
+
+	return &SCRAMCredential{
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		Iterations: iterations,
+		StoredKey:  base64.StdEncoding.EncodeToString(storedKey[:]),
+		ServerKey:  base64.StdEncoding.EncodeToString(serverKey),
+	}
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
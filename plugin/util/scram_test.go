@@ -0,0 +1,31 @@
+package util
+
+import "testing"
+
+func TestDeriveSCRAMCredentialIsDeterministic(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+
+	a := deriveSCRAMCredential("hunter2", salt, DefaultSCRAMIterations)
+	b := deriveSCRAMCredential("hunter2", salt, DefaultSCRAMIterations)
+
+	if a.StoredKey != b.StoredKey || a.ServerKey != b.ServerKey {
+		t.Fatalf("expected identical password+salt+iterations to derive identical keys, got %+v and %+v", a, b)
+	}
+	if a.Salt == "" || a.StoredKey == "" || a.ServerKey == "" {
+		t.Fatalf("expected non-empty salt/StoredKey/ServerKey, got %+v", a)
+	}
+	if a.Iterations != DefaultSCRAMIterations {
+		t.Fatalf("expected iterations %d, got %d", DefaultSCRAMIterations, a.Iterations)
+	}
+}
+
+func TestDeriveSCRAMCredentialDiffersByPassword(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+
+	a := deriveSCRAMCredential("hunter2", salt, DefaultSCRAMIterations)
+	b := deriveSCRAMCredential("hunter3", salt, DefaultSCRAMIterations)
+
+	if a.StoredKey == b.StoredKey {
+		t.Fatalf("expected different passwords to derive different StoredKeys")
+	}
+}
@@ -2,7 +2,8 @@ package util
 
 import (
 	"fmt"
-	"strconv"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
@@ -18,14 +19,84 @@ func NewSecretsClient(logger hclog.Logger) *SecretsClient {
 type SecretsClient struct {
 	adClient *client.Client
 	Logger   hclog.Logger
+
+	// DirectoryType selects which client.DirectoryProvider backs password
+	// age and enable/disable operations. It defaults to Active Directory.
+	DirectoryType client.DirectoryType
+
+	// BindSASLMechanism selects what UpdateRootCredential rotates: a
+	// password for the default SimpleBind, or a re-issued client
+	// certificate for ExternalBind.
+	BindSASLMechanism client.BindSASLMechanism
+
+	// CertIssuer re-issues the client certificate UpdateRootCredential
+	// writes to the bind DN when BindSASLMechanism is ExternalBind. It's
+	// unused, and may be nil, for SimpleBind.
+	CertIssuer client.CertIssuer
+}
+
+func (c *SecretsClient) directoryProvider() (client.DirectoryProvider, error) {
+	return client.ProviderFor(c.DirectoryType)
+}
+
+// HierarchicalNamePattern is the regex a role name must match wherever it's
+// accepted as part of a framework path, e.g.
+// "rotate-role/" + HierarchicalNamePattern. It allows path segments of
+// letters, numbers, underscores, dashes and dots, separated by single
+// forward slashes, mirroring the GenericNameWithForwardSlashRegex pattern
+// used by the sibling openldap plugin. This chunk of the repo doesn't carry
+// the plugin's path_*.go route definitions, so the framework.Path wiring
+// itself lives outside it; splitHierarchicalName enforces the same pattern
+// so a name that wouldn't match the route also can't reach the LDAP layer.
+const HierarchicalNamePattern = `\w(([\w.-]+)?\w)?(/\w(([\w.-]+)?\w)?)*`
+
+var hierarchicalNameRegex = regexp.MustCompile(`^` + HierarchicalNamePattern + `$`)
+
+// splitHierarchicalName breaks a role name like "svc/prod/db1" into the OU
+// path ("svc/prod") and the leaf account name ("db1") that it maps to. Role
+// names without a "/" are left untouched, with an empty OU path. It rejects
+// anything not matching HierarchicalNamePattern, including empty names and
+// names with empty path segments (e.g. "svc//db1", "/svc/db1", "svc/db1/"),
+// which would otherwise produce a malformed search base.
+func splitHierarchicalName(serviceAccountName string) (ouPath []string, accountName string, err error) {
+	if !hierarchicalNameRegex.MatchString(serviceAccountName) {
+		return nil, "", fmt.Errorf("%q is not a valid service account name; it must match %s", serviceAccountName, HierarchicalNamePattern)
+	}
+
+	if !strings.Contains(serviceAccountName, "/") {
+		return nil, serviceAccountName, nil
+	}
+
+	segments := strings.Split(serviceAccountName, "/")
+	return segments[:len(segments)-1], segments[len(segments)-1], nil
+}
+
+// searchBase resolves the LDAP search base to use for a (possibly
+// hierarchical) role name. Each path segment becomes a nested OU under
+// defaultBase, outermost segment last, e.g. "svc/prod" under
+// "DC=example,DC=com" becomes "OU=prod,OU=svc,DC=example,DC=com".
+func searchBase(defaultBase string, ouPath []string) string {
+	if len(ouPath) == 0 {
+		return defaultBase
+	}
+	parts := make([]string, 0, len(ouPath)+1)
+	for i := len(ouPath) - 1; i >= 0; i-- {
+		parts = append(parts, fmt.Sprintf("OU=%s", ouPath[i]))
+	}
+	parts = append(parts, defaultBase)
+	return strings.Join(parts, ",")
 }
 
 func (c *SecretsClient) Get(conf *client.ADConf, serviceAccountName string) (*client.Entry, error) {
+	ouPath, accountName, err := splitHierarchicalName(serviceAccountName)
+	if err != nil {
+		return nil, err
+	}
 	filters := map[*client.Field][]string{
-		client.FieldRegistry.UserPrincipalName: {serviceAccountName},
+		client.FieldRegistry.UserPrincipalName: {accountName},
 	}
 
-	entries, err := c.adClient.Search(conf, conf.UserDN, filters)
+	entries, err := c.adClient.Search(conf, searchBase(conf.UserDN, ouPath), filters)
 	if err != nil {
 		return nil, err
 	}
@@ -45,33 +116,48 @@ func (c *SecretsClient) GetPasswordLastSet(conf *client.ADConf, serviceAccountNa
 		return time.Time{}, err
 	}
 
-	values, found := entry.Get(client.FieldRegistry.PasswordLastSet)
-	if !found {
-		return time.Time{}, fmt.Errorf("%+v lacks a PasswordLastSet field", entry)
+	provider, err := c.directoryProvider()
+	if err != nil {
+		return time.Time{}, err
 	}
+	return provider.PasswordLastSet(entry)
+}
 
-	if len(values) != 1 {
-		return time.Time{}, fmt.Errorf("expected only one value for PasswordLastSet, but received %s", values)
+func (c *SecretsClient) UpdatePassword(conf *client.ADConf, serviceAccountName string, newPassword string) error {
+	entry, err := c.Get(conf, serviceAccountName)
+	if err != nil {
+		return err
 	}
+	return c.updateEntryPassword(conf, entry, newPassword)
+}
 
-	ticks := values[0]
-	if ticks == "0" {
-		// password has never been rolled in Active Directory, only created
-		return time.Time{}, nil
+// updateEntryPassword rotates newPassword onto an already-resolved entry,
+// modifying it directly by its DistinguishedName rather than re-resolving it
+// by UserPrincipalName. UpdatePasswordMany uses this to pipeline modifies
+// against entries it already looked up in bulk via GetMany.
+func (c *SecretsClient) updateEntryPassword(conf *client.ADConf, entry *client.Entry, newPassword string) error {
+	provider, err := c.directoryProvider()
+	if err != nil {
+		return err
 	}
-
-	t, err := client.ParseTicks(ticks)
+	attribute, value, err := provider.EncodePassword(newPassword)
 	if err != nil {
-		return time.Time{}, err
+		return err
 	}
-	return t, nil
+	return c.updateEntryAttribute(conf, entry, attribute, value)
 }
 
-func (c *SecretsClient) UpdatePassword(conf *client.ADConf, serviceAccountName string, newPassword string) error {
+// updateEntryAttribute writes a single attribute value onto an
+// already-resolved entry, searching for it by DistinguishedName rather than
+// by the broader UserPrincipalName filter used to originally find it.
+func (c *SecretsClient) updateEntryAttribute(conf *client.ADConf, entry *client.Entry, attribute *client.Field, value string) error {
 	filters := map[*client.Field][]string{
-		client.FieldRegistry.UserPrincipalName: {serviceAccountName},
+		client.FieldRegistry.DistinguishedName: {entry.DN},
 	}
-	return c.adClient.UpdatePassword(conf, conf.UserDN, filters, newPassword)
+	newValues := map[*client.Field][]string{
+		attribute: {value},
+	}
+	return c.adClient.UpdateEntry(conf, entry.DN, filters, newValues)
 }
 
 func (c *SecretsClient) UpdateRootPassword(conf *client.ADConf, bindDN string, newPassword string) error {
@@ -87,70 +173,74 @@ func (c *SecretsClient) UpdateRootPassword(conf *client.ADConf, bindDN string, n
 	return c.adClient.UpdatePassword(conf, conf.BindDN, filters, newPassword)
 }
 
-// DisableAccount if account is not already disabled by updating the UserAccountControl attribute
-func (c *SecretsClient) DisableAccount(conf *client.ADConf, serviceAccountName string) error {
-	uacFlag, err := c.getUserAccountControl(conf, serviceAccountName)
-	if err != nil {
-		return err
-	}
-	if !uacFlag.Has(client.ACCOUNTDISABLE) {
-		c.Logger.Debug(fmt.Sprintf("Account before disabled - UAC for %s, %x", serviceAccountName, uacFlag))
-		uacFlag.Add(client.ACCOUNTDISABLE)
-		return c.updateUAC(conf, serviceAccountName, uacFlag)
-	} else {
-		c.Logger.Debug(fmt.Sprintf("Account already disabled - UAC for %s, %x", serviceAccountName, uacFlag))
-		return nil
+// UpdateRootCredential rotates the root bind credential at bindDN, dispatching
+// on c.BindSASLMechanism: UpdateRootPassword's password rotation for the
+// default SimpleBind, or a freshly issued client certificate for
+// ExternalBind. newPassword is ignored for ExternalBind.
+func (c *SecretsClient) UpdateRootCredential(conf *client.ADConf, bindDN string, newPassword string) error {
+	switch c.BindSASLMechanism {
+	case client.SimpleBind:
+		return c.UpdateRootPassword(conf, bindDN, newPassword)
+	case client.ExternalBind:
+		if c.CertIssuer == nil {
+			return fmt.Errorf("bind_sasl_mechanism is %q but no CertIssuer is configured to re-issue a certificate", client.ExternalBind)
+		}
+		derCert, _, err := c.CertIssuer.IssueCertificate()
+		if err != nil {
+			return fmt.Errorf("failed to issue a new bind certificate: %w", err)
+		}
+		return c.UpdateRootCertificate(conf, bindDN, derCert)
+	default:
+		return fmt.Errorf("unsupported bind_sasl_mechanism %q", c.BindSASLMechanism)
 	}
+}
 
+// DisableAccount disables serviceAccountName's account, if it isn't disabled already.
+func (c *SecretsClient) DisableAccount(conf *client.ADConf, serviceAccountName string) error {
+	return c.setDisabled(conf, serviceAccountName, true)
 }
 
-// EnableAccount if account is not already enabled by updating the UserAccountControl attribute
+// EnableAccount enables serviceAccountName's account, if it isn't enabled already.
 func (c *SecretsClient) EnableAccount(conf *client.ADConf, serviceAccountName string) error {
-	uacFlag, err := c.getUserAccountControl(conf, serviceAccountName)
+	return c.setDisabled(conf, serviceAccountName, false)
+}
+
+// setDisabled dispatches through the configured DirectoryProvider to update
+// the directory-specific attribute (e.g. Active Directory's
+// UserAccountControl, FreeIPA's nsAccountLock) that controls whether an
+// account is disabled.
+func (c *SecretsClient) setDisabled(conf *client.ADConf, serviceAccountName string, disabled bool) error {
+	entry, err := c.Get(conf, serviceAccountName)
 	if err != nil {
 		return err
 	}
-	if uacFlag.Has(client.ACCOUNTDISABLE) {
-		c.Logger.Debug(fmt.Sprintf("Account before enable - UAC for %s, %x", serviceAccountName, uacFlag))
-		uacFlag.Clear(client.ACCOUNTDISABLE)
-		return c.updateUAC(conf, serviceAccountName, uacFlag)
-	} else {
-		c.Logger.Debug(fmt.Sprintf("Account already enabled - UAC for %s, %x", serviceAccountName, uacFlag))
-		return nil
-	}
+	return c.setEntryDisabled(conf, serviceAccountName, entry, disabled)
 }
 
-// Update the UserAccountControl attribute
-func (c *SecretsClient) updateUAC(conf *client.ADConf, serviceAccountName string, uac client.Bits) error {
-	c.Logger.Debug(fmt.Sprintf("Account updated for %s, %x", serviceAccountName, uac))
-	uacInt := uint64(uac)
-
-	filters := map[*client.Field][]string{
-		client.FieldRegistry.UserPrincipalName: {serviceAccountName},
-	}
-	newValues := map[*client.Field][]string{
-		client.FieldRegistry.UserAccountControl: {strconv.FormatUint(uacInt, 10)},
+// setEntryDisabled applies disabled to an already-resolved entry, modifying
+// it directly by its DistinguishedName. DisableAccountMany uses this to
+// pipeline modifies against entries it already looked up in bulk via
+// GetMany.
+func (c *SecretsClient) setEntryDisabled(conf *client.ADConf, serviceAccountName string, entry *client.Entry, disabled bool) error {
+	provider, err := c.directoryProvider()
+	if err != nil {
+		return err
 	}
-	return c.adClient.UpdateEntry(conf, conf.UserDN, filters, newValues)
-}
 
-// Return the UserAccountControl attribute from Active Directory
-func (c *SecretsClient) getUserAccountControl(conf *client.ADConf, serviceAccountName string) (client.Bits, error) {
-	entry, err := c.Get(conf, serviceAccountName)
+	alreadyDisabled, err := provider.IsDisabled(entry)
 	if err != nil {
-		return client.Bits(0), err
+		return err
 	}
-	values, found := entry.Get(client.FieldRegistry.UserAccountControl)
-	if !found {
-		return client.Bits(0), fmt.Errorf("%+v lacks a UserAccountControl field", entry)
+	if alreadyDisabled == disabled {
+		c.Logger.Debug(fmt.Sprintf("account %s already has disabled=%t", serviceAccountName, disabled))
+		return nil
 	}
 
-	if len(values) != 1 {
-		return client.Bits(0), fmt.Errorf("expected only one value for UserAccountControl, but received %s", values)
+	attribute, value, err := provider.EncodeDisabled(entry, disabled)
+	if err != nil {
+		return err
 	}
 
-	val := values[0]
-	c.Logger.Debug(fmt.Sprintf("Get UAC for %s, %s", serviceAccountName, val))
-	uac64, err := strconv.ParseUint(val, 10, 32)
-	return client.Bits(uint32(uac64)), nil
+	c.Logger.Debug(fmt.Sprintf("setting disabled=%t for %s", disabled, serviceAccountName))
+	return c.updateEntryAttribute(conf, entry, attribute, value)
 }